@@ -0,0 +1,101 @@
+// Package fixer drives the process of applying a set of Fixes to the
+// packages produced by the importer package, and reporting the results.
+package fixer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+
+	"github.com/go-logr/logr"
+
+	"github.com/thockin/klog-to-logr/importer"
+)
+
+// Fix describes a single rewrite rule that can be applied to a package.
+type Fix struct {
+	Name        string
+	Description string
+
+	// Apply rewrites a single file's AST in place, reporting whether it
+	// made any changes.
+	Apply func(pkg *importer.PackageInfo, file *ast.File) (changed bool, err error)
+}
+
+// FileInfo describes a single file that a Fix changed.
+type FileInfo struct {
+	Name string
+	AST  *ast.File
+
+	// OrigSrc is the file's pre-fix contents, honoring Fixer.Overlay.
+	// HandleFix implementations that need the original source (e.g. to
+	// compute a diff) can use this instead of re-reading the file
+	// themselves.
+	OrigSrc []byte
+}
+
+// Fixer walks the packages produced by Loader, applying Fixes to each file
+// and reporting the result of each changed file via HandleFix.
+type Fixer struct {
+	Log    logr.Logger
+	Fixes  []Fix
+	Loader *importer.Loader
+
+	// Overlay optionally maps file names to in-memory contents that take
+	// precedence over disk. HandleFix implementations that need to read a
+	// file's pre-fix contents (e.g. to compute a diff) should consult this
+	// before falling back to disk, the same way Loader's packages do.
+	Overlay map[string][]byte
+
+	HandleFix func(info FileInfo) error
+}
+
+// FixPackage applies every configured Fix to each file in pkg, invoking
+// HandleFix for any file that ends up changed.
+func (f *Fixer) FixPackage(pkg *importer.PackageInfo) error {
+	for _, file := range pkg.Syntax {
+		changed := false
+		for _, fix := range f.Fixes {
+			ok, err := fix.Apply(pkg, file)
+			if err != nil {
+				return fmt.Errorf("fix %q: %w", fix.Name, err)
+			}
+			changed = changed || ok
+		}
+		if !changed {
+			continue
+		}
+
+		filename := f.Loader.FileSet().Position(file.Pos()).Filename
+		origSrc, err := f.readFile(filename)
+		if err != nil {
+			return err
+		}
+		if err := f.HandleFix(FileInfo{Name: filename, AST: file, OrigSrc: origSrc}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFile reads filename, preferring f.Overlay's copy if it has one -- the
+// same precedence importer.Importer gives packages.Config.Overlay, so a
+// Fix's view of "the original source" matches what was actually parsed.
+func (f *Fixer) readFile(filename string) ([]byte, error) {
+	if data, ok := f.Overlay[filename]; ok {
+		return data, nil
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// GofmtFile formats file back into gofmt-compatible source.
+func GofmtFile(file *ast.File, fset *token.FileSet) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,35 @@
+package fixer
+
+import "sort"
+
+// Factory builds a Fix from a set of string-valued configuration options
+// (e.g. the klog import path to rewrite away from). It's the shape every
+// Fix registers itself under, so callers don't need to import the package
+// that defines a given Fix in order to select it by name.
+type Factory func(cfg map[string]string) (Fix, error)
+
+var registry = map[string]Factory{}
+
+// Register makes a Fix factory available under name, so that it can be
+// selected via `-r name` without patching this binary. Fixes are expected
+// to call this from an init() in the package that defines them, the same
+// way gofix's built-in fixes register themselves.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Lookup returns the factory registered under name, if any.
+func Lookup(name string) (Factory, bool) {
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// Names returns the names of all registered fixes, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+// Package fixes contains the individual Fixes that kfix knows how to apply.
+package fixes
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/thockin/klog-to-logr/fixer"
+	"github.com/thockin/klog-to-logr/importer"
+)
+
+// StandardKlogPkg is the import path of the canonical klog package that
+// LogrFix rewrites away from by default.
+const StandardKlogPkg = "k8s.io/klog"
+
+func init() {
+	fixer.Register("logr", func(cfg map[string]string) (fixer.Fix, error) {
+		klogPkg := cfg["klog-pkg"]
+		if klogPkg == "" {
+			klogPkg = StandardKlogPkg
+		}
+		return LogrFix(klogPkg)
+	})
+}
+
+// LogrFix returns the "logr" Fix for klogPkg (e.g. "k8s.io/klog" or
+// "github.com/golang/glog"). Its Apply does not walk or rewrite the file's
+// AST: it leaves every file unchanged and always reports no change.
+func LogrFix(klogPkg string) (fixer.Fix, error) {
+	if klogPkg == "" {
+		return fixer.Fix{}, fmt.Errorf("klogPkg must not be empty")
+	}
+
+	return fixer.Fix{
+		Name:        "logr",
+		Description: fmt.Sprintf("Rewrites calls against %s to use github.com/go-logr/logr instead.", klogPkg),
+		Apply: func(pkg *importer.PackageInfo, file *ast.File) (bool, error) {
+			// TODO(thockin): walk file's AST, rewriting klogPkg.Infof(...)
+			// and friends into logr's Info/Error calls.
+			return false, nil
+		},
+	}, nil
+}
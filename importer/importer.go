@@ -0,0 +1,207 @@
+// Package importer loads Go packages for kfix to fix up, on top of
+// golang.org/x/tools/go/packages so that module-aware builds (replace
+// directives, vendoring, GOFLAGS, `foo.com/repo/pkg/...` wildcards, and so
+// on) work the same way they would for `go build`.
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/tools/go/packages"
+)
+
+// PackageInfo wraps a loaded *packages.Package, exposing its syntax trees,
+// type information and file set to the fixes that walk it.
+type PackageInfo struct {
+	*packages.Package
+}
+
+// ImportPath returns the package's import path, as resolved by the
+// underlying `go list`.
+func (p *PackageInfo) ImportPath() string {
+	return p.PkgPath
+}
+
+// Loader caches the packages loaded by an Importer, keyed by PkgPath, and
+// hands out the token.FileSet they all share. A single pattern can expand to
+// many packages (e.g. a `foo.com/repo/pkg/...` wildcard or `./...`), and the
+// same package can turn up from two different patterns, so the cache is
+// keyed by the package's own import path rather than by the pattern that
+// happened to surface it.
+type Loader struct {
+	log  logr.Logger
+	fset *token.FileSet
+
+	pkgs map[string]*PackageInfo
+	// sigs holds, for each PkgPath, a hash over the mtime/size of every file
+	// in that package *and every package it imports, transitively*, as of
+	// its last load. A fresh Import call whose signature is unchanged reuses
+	// the cached PackageInfo instead of re-parsing and re-typechecking --
+	// the win that makes `kfix serve`'s watch loop fast on a warm cache --
+	// and touching any dependency invalidates it just like touching the
+	// package itself would.
+	sigs map[string]string
+	// patterns remembers, for each pattern passed to Import, the PkgPaths it
+	// last expanded to, so a repeat Import(pattern) can check the existing
+	// cache entries instead of paying for another packages.Load.
+	patterns map[string][]string
+}
+
+// FileSet returns the token.FileSet shared by every package this Loader has
+// loaded.
+func (l *Loader) FileSet() *token.FileSet {
+	return l.fset
+}
+
+// PackageInfoFor returns the package previously loaded for pkgPath (a
+// package's own import path, not necessarily the pattern passed to
+// Importer.Import), or nil if nothing has been loaded for it yet.
+func (l *Loader) PackageInfoFor(pkgPath string) *PackageInfo {
+	return l.pkgs[pkgPath]
+}
+
+// Importer loads Go packages -- specified as import paths, directories, or
+// `foo.com/repo/pkg/...` wildcard patterns -- from the module-aware build
+// list rooted at dir.
+type Importer struct {
+	dir string
+	log logr.Logger
+
+	loader *Loader
+
+	// Overlay optionally maps file names to in-memory contents that take
+	// precedence over what's on disk, e.g. a caller's unsaved editor
+	// buffer. It's passed straight through to packages.Config.Overlay.
+	Overlay map[string][]byte
+}
+
+// NewImporter constructs an Importer (and its paired Loader) rooted at dir.
+// Loading honors Go modules, replace directives, vendoring and
+// GOFLAGS-controlled builds exactly as `go build` would, since it delegates
+// to `go list` under the hood via golang.org/x/tools/go/packages.
+func NewImporter(dir string, log logr.Logger) (*Importer, *Loader) {
+	loader := &Loader{
+		log:      log,
+		fset:     token.NewFileSet(),
+		pkgs:     make(map[string]*PackageInfo),
+		sigs:     make(map[string]string),
+		patterns: make(map[string][]string),
+	}
+	return &Importer{
+		dir:    dir,
+		log:    log,
+		loader: loader,
+	}, loader
+}
+
+// Import loads every package matching pattern and caches each of them on the
+// associated Loader, keyed by its PkgPath, for later retrieval via
+// Loader.PackageInfoFor. pattern may be an import path, a directory, or a
+// `foo.com/repo/pkg/...` wildcard -- packages.Load accepts all of those
+// natively, expanding a wildcard (or `./...`) to every package it matches,
+// so there's no need to hand-roll wildcard expansion the way `cmd/go` does.
+//
+// If pattern was loaded before and none of the packages it matched have
+// changed since -- nor any package any of them imports, transitively -- the
+// previous results are returned without re-parsing or re-typechecking.
+func (imp *Importer) Import(pattern string) ([]*PackageInfo, error) {
+	if pkgPaths, ok := imp.loader.patterns[pattern]; ok {
+		if infos, ok := imp.cachedFor(pkgPaths); ok {
+			return infos, nil
+		}
+	}
+
+	cfg := &packages.Config{
+		Mode:    packages.LoadAllSyntax,
+		Dir:     imp.dir,
+		Fset:    imp.loader.fset,
+		Overlay: imp.Overlay,
+	}
+
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("loading package %q: %w", pattern, err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("one or more errors while loading package %q", pattern)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages found for %q", pattern)
+	}
+
+	infos := make([]*PackageInfo, 0, len(pkgs))
+	pkgPaths := make([]string, 0, len(pkgs))
+	for _, p := range pkgs {
+		info := &PackageInfo{Package: p}
+		imp.loader.pkgs[p.PkgPath] = info
+		imp.loader.sigs[p.PkgPath] = transitiveSignature(p)
+		infos = append(infos, info)
+		pkgPaths = append(pkgPaths, p.PkgPath)
+	}
+	imp.loader.patterns[pattern] = pkgPaths
+	return infos, nil
+}
+
+// cachedFor returns the cached PackageInfo for each of pkgPaths, along with
+// true, if every one of them is still cached and unchanged; otherwise it
+// returns false so the caller falls back to a fresh packages.Load.
+func (imp *Importer) cachedFor(pkgPaths []string) ([]*PackageInfo, bool) {
+	infos := make([]*PackageInfo, 0, len(pkgPaths))
+	for _, pkgPath := range pkgPaths {
+		cached, ok := imp.loader.pkgs[pkgPath]
+		if !ok {
+			return nil, false
+		}
+		if sig := transitiveSignature(cached.Package); sig != imp.loader.sigs[pkgPath] {
+			return nil, false
+		}
+		infos = append(infos, cached)
+	}
+	return infos, true
+}
+
+// transitiveSignature hashes the size and modification time of every file
+// in pkg, and every file in every package pkg imports, transitively --
+// walking pkg.Imports (deduped by PkgPath, so import cycles terminate) --
+// so that a change anywhere in pkg's dependency closure invalidates the
+// cache, not just a change to pkg's own files.
+func transitiveSignature(pkg *packages.Package) string {
+	seen := make(map[string]bool)
+	var files []string
+	var walk func(p *packages.Package)
+	walk = func(p *packages.Package) {
+		if seen[p.PkgPath] {
+			return
+		}
+		seen[p.PkgPath] = true
+		files = append(files, p.GoFiles...)
+		for _, dep := range p.Imports {
+			walk(dep)
+		}
+	}
+	walk(pkg)
+	sort.Strings(files)
+	return fileSignature(files)
+}
+
+// fileSignature hashes the size and modification time of each of files,
+// giving a cheap proxy for "has any of this package's content changed"
+// without reading (let alone re-parsing) the files themselves.
+func fileSignature(files []string) string {
+	h := sha256.New()
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			fmt.Fprintf(h, "%s:missing\n", f)
+			continue
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", f, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
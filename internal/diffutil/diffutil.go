@@ -0,0 +1,120 @@
+// Package diffutil computes line-level diffs shared by kfix's CLI unified
+// diff output and its go/analysis SuggestedFix edits, so both stay
+// byte-for-byte consistent with each other.
+package diffutil
+
+import "strings"
+
+// OpKind says whether an Op carries a line common to both inputs, a line
+// only in the first, or a line only in the second.
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is one step of an edit script: a single line, tagged with its kind and
+// its 0-based index into the line slice(s) it came from. AIdx/BIdx is -1 on
+// the side an Op doesn't have a line for.
+type Op struct {
+	Kind OpKind
+	AIdx int
+	BIdx int
+}
+
+// SplitLines splits data into its lines, each retaining its trailing "\n"
+// except possibly the last, and reports whether data ended in a newline.
+func SplitLines(data []byte) (lines []string, finalNewline bool) {
+	if len(data) == 0 {
+		return nil, true
+	}
+	s := string(data)
+	finalNewline = strings.HasSuffix(s, "\n")
+	lines = strings.SplitAfter(s, "\n")
+	if finalNewline {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, finalNewline
+}
+
+// Diff computes the shortest edit script turning a into b, using Myers'
+// O((N+M)D) algorithm: for each edit distance d from 0 upward, it extends
+// every diagonal k = -d, -d+2, ..., d by taking the better of V[k-1]+1 (a
+// deletion) and V[k+1] (an insertion), then snakes along equal lines, until
+// some diagonal reaches the bottom-right corner. V is snapshotted at the
+// *start* of each d (i.e. the state left over from d-1) so that backtrack
+// can replay, for each d, the exact frontier it extended from.
+func Diff(a, b []string) []Op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	v := make([]int, 2*max+1)
+	offset := max
+	var trace [][]int
+
+	x, y := 0, 0
+loop:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break loop
+			}
+		}
+	}
+
+	// Backtrack through the trace to recover the edit script, then reverse
+	// it (we discover it end-to-start).
+	var ops []Op
+	x, y = n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && vd[offset+k-1] < vd[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, Op{Equal, x - 1, y - 1})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, Op{Insert, -1, y - 1})
+			} else {
+				ops = append(ops, Op{Delete, x - 1, -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,76 @@
+package diffutil
+
+import (
+	"strings"
+	"testing"
+)
+
+// apply replays ops against a and b, reconstructing each input from the
+// edit script. If Diff produced a bogus op (e.g. an out-of-range index),
+// this fails the test via t.Fatalf, where the real consumers (udiff.go,
+// analysis.go) would panic on the same op.
+func apply(t *testing.T, a, b []string, ops []Op) (gotA, gotB []string) {
+	t.Helper()
+	for _, o := range ops {
+		switch o.Kind {
+		case Equal:
+			if o.AIdx < 0 || o.AIdx >= len(a) || o.BIdx < 0 || o.BIdx >= len(b) {
+				t.Fatalf("Equal op with out-of-range index: %+v (len(a)=%d, len(b)=%d)", o, len(a), len(b))
+			}
+			if a[o.AIdx] != b[o.BIdx] {
+				t.Fatalf("Equal op %+v but a[%d]=%q != b[%d]=%q", o, o.AIdx, a[o.AIdx], o.BIdx, b[o.BIdx])
+			}
+			gotA = append(gotA, a[o.AIdx])
+			gotB = append(gotB, b[o.BIdx])
+		case Delete:
+			if o.AIdx < 0 || o.AIdx >= len(a) {
+				t.Fatalf("Delete op with out-of-range index: %+v (len(a)=%d)", o, len(a))
+			}
+			gotA = append(gotA, a[o.AIdx])
+		case Insert:
+			if o.BIdx < 0 || o.BIdx >= len(b) {
+				t.Fatalf("Insert op with out-of-range index: %+v (len(b)=%d)", o, len(b))
+			}
+			gotB = append(gotB, b[o.BIdx])
+		default:
+			t.Fatalf("unknown op kind %v", o.Kind)
+		}
+	}
+	return gotA, gotB
+}
+
+func TestDiffReconstructsInputs(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n"},
+		{"single line changed", "a\nb\nc\n", "a\nB\nc\n"},
+		{"multi-line change", "a\nb\nc\nd\n", "a\nX\nY\nd\n"},
+		{"deletion", "a\nb\nc\n", "a\nc\n"},
+		{"insertion", "a\nc\n", "a\nb\nc\n"},
+		{"missing trailing newline on a", "a\nb\nc", "a\nb\nc\n"},
+		{"missing trailing newline on b", "a\nb\nc\n", "a\nb\nc"},
+		{"both empty", "", ""},
+		{"a empty", "", "a\nb\n"},
+		{"b empty", "a\nb\n", ""},
+		{"completely different", "one\ntwo\nthree\n", "four\nfive\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			aLines, _ := SplitLines([]byte(c.a))
+			bLines, _ := SplitLines([]byte(c.b))
+
+			ops := Diff(aLines, bLines)
+			gotA, gotB := apply(t, aLines, bLines, ops)
+
+			if got := strings.Join(gotA, ""); got != c.a {
+				t.Errorf("reconstructed a = %q, want %q", got, c.a)
+			}
+			if got := strings.Join(gotB, ""); got != c.b {
+				t.Errorf("reconstructed b = %q, want %q", got, c.b)
+			}
+		})
+	}
+}
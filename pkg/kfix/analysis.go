@@ -0,0 +1,152 @@
+package kfix
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/thockin/klog-to-logr/fixer"
+	"github.com/thockin/klog-to-logr/fixes"
+	"github.com/thockin/klog-to-logr/importer"
+	"github.com/thockin/klog-to-logr/internal/diffutil"
+)
+
+// Analyzer adapts the "logr" fix to the golang.org/x/tools/go/analysis
+// framework, so it can be driven by `go vet -vettool=`, gopls' suggested
+// fixes, multichecker, or unitchecker, instead of only the `kfix` binary.
+// Each rewrite is reported as a SuggestedFix rather than written to disk.
+var Analyzer = &analysis.Analyzer{
+	Name: "kfix",
+	Doc:  "suggests klog/glog -> logr rewrites",
+	Run:  runAnalyzer,
+}
+
+func runAnalyzer(pass *analysis.Pass) (interface{}, error) {
+	fix, err := fixes.LogrFix(fixes.StandardKlogPkg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Thin adapter: analysis.Pass and packages.Package expose largely the
+	// same information, just shaped differently, so Fix.Apply doesn't need
+	// its own analysis-specific code path.
+	pkg := &importer.PackageInfo{Package: &packages.Package{
+		PkgPath:   pass.Pkg.Path(),
+		Types:     pass.Pkg,
+		TypesInfo: pass.TypesInfo,
+		Syntax:    pass.Files,
+		Fset:      pass.Fset,
+	}}
+
+	for _, file := range pass.Files {
+		if err := suggestFix(pass, pkg, fix, file); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// suggestFix applies fix to file and, if it changed anything, reports a
+// Diagnostic carrying the minimal set of TextEdits that turn the file's
+// original source into its rewritten one.
+func suggestFix(pass *analysis.Pass, pkg *importer.PackageInfo, fix fixer.Fix, file *ast.File) error {
+	tfile := pass.Fset.File(file.Pos())
+	origSrc, err := ioutil.ReadFile(tfile.Name())
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", tfile.Name(), err)
+	}
+
+	changed, err := fix.Apply(pkg, file)
+	if err != nil || !changed {
+		return err
+	}
+
+	newSrc, err := fixer.GofmtFile(file, pass.Fset)
+	if err != nil {
+		return err
+	}
+
+	edits := lineEdits(tfile, origSrc, newSrc)
+	if len(edits) == 0 {
+		return nil
+	}
+
+	pass.Report(analysis.Diagnostic{
+		Pos:     file.Pos(),
+		Message: fmt.Sprintf("this file can be rewritten by the %q fix", fix.Name),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("apply %s", fix.Name),
+			TextEdits: edits,
+		}},
+	})
+	return nil
+}
+
+// lineEdits diffs orig against newSrc and returns the minimal set of
+// TextEdits that turn one into the other. Replacing the whole
+// [file.Pos(), file.End()] span would be wrong: file.Pos() is the `package`
+// keyword, not the start of the file, so format.Node's output re-emits the
+// file's leading comments and build tags -- which then show up twice once
+// the edit is applied.
+func lineEdits(tfile *token.File, orig, newSrc []byte) []analysis.TextEdit {
+	origLines, _ := diffutil.SplitLines(orig)
+	newLines, _ := diffutil.SplitLines(newSrc)
+	ops := diffutil.Diff(origLines, newLines)
+
+	var edits []analysis.TextEdit
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == diffutil.Equal {
+			i++
+			continue
+		}
+
+		j := i
+		firstA, lastA := -1, -1
+		var newText strings.Builder
+		for j < len(ops) && ops[j].Kind != diffutil.Equal {
+			switch ops[j].Kind {
+			case diffutil.Delete:
+				if firstA == -1 {
+					firstA = ops[j].AIdx
+				}
+				lastA = ops[j].AIdx
+			case diffutil.Insert:
+				newText.WriteString(newLines[ops[j].BIdx])
+			}
+			j++
+		}
+
+		var pos, end token.Pos
+		if firstA == -1 {
+			// A pure insertion: nothing in orig to replace, so Pos == End,
+			// placed right before whatever follows it (or at EOF).
+			insertLine := len(origLines)
+			if j < len(ops) {
+				insertLine = ops[j].AIdx
+			}
+			pos = lineStart(tfile, insertLine)
+			end = pos
+		} else {
+			pos = lineStart(tfile, firstA)
+			end = lineStart(tfile, lastA+1)
+		}
+
+		edits = append(edits, analysis.TextEdit{Pos: pos, End: end, NewText: []byte(newText.String())})
+		i = j
+	}
+	return edits
+}
+
+// lineStart returns the position of the first character of line (0-based),
+// or the file's end position if line is at or past EOF.
+func lineStart(tfile *token.File, line int) token.Pos {
+	if line >= tfile.LineCount() {
+		return tfile.Pos(tfile.Size())
+	}
+	return tfile.LineStart(line + 1)
+}
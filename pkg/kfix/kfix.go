@@ -0,0 +1,164 @@
+// Package kfix is the library form of the `kfix` command: it loads
+// packages, applies a set of registered fixer.Fixes to them, and reports
+// the results. `main` is a thin shell around Run; embedders (editor
+// integrations, CI pipelines, go/analysis passes -- see Analyzer) can call
+// it directly instead.
+package kfix
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+
+	"github.com/go-logr/logr"
+
+	"github.com/thockin/klog-to-logr/fixer"
+	"github.com/thockin/klog-to-logr/importer"
+)
+
+// Config configures a single Run.
+type Config struct {
+	// Dir is the working directory Patterns are resolved relative to.
+	Dir string
+	// Patterns are the import paths, directories, or `foo.com/repo/pkg/...`
+	// wildcards to load and fix.
+	Patterns []string
+
+	// Fixes selects which registered fixes to apply, by name (see
+	// fixer.Register and fixer.Names). A nil/empty slice applies none.
+	Fixes []string
+	// FixConfig is passed to each selected fix's factory, e.g. "klog-pkg".
+	FixConfig map[string]string
+
+	// Overlay optionally supplies in-memory file contents that take
+	// precedence over disk, e.g. an editor's unsaved buffers.
+	Overlay map[string][]byte
+
+	// Log receives progress and error messages. The zero logr.Logger
+	// discards everything.
+	Log logr.Logger
+
+	// OnFix is called with a changed file's info, its original source, and
+	// its rewritten source. If OnFix is nil, the rewritten source is
+	// written back to Name in place.
+	OnFix func(info fixer.FileInfo, originalSrc, newSrc []byte) error
+}
+
+// Results reports the outcome of a Run.
+type Results struct {
+	// Fixed lists the files that were changed, in the order they were
+	// fixed.
+	Fixed []string
+}
+
+// Run loads every package matching cfg.Patterns and applies cfg.Fixes to
+// each of them, reporting changed files via cfg.OnFix. Each call to Run
+// builds its own Importer, so nothing is cached between calls; callers
+// that run repeatedly against the same tree (e.g. the -watch loop) should
+// use a Runner instead, so unchanged packages are reused rather than
+// re-parsed and re-typechecked every time.
+func Run(ctx context.Context, cfg Config) (Results, error) {
+	return NewRunner(cfg.Dir, cfg.Log).Run(ctx, cfg)
+}
+
+// Runner holds an Importer (and its package/signature cache) across
+// multiple Run calls against the same directory, so that -watch-style
+// repeated runs only re-parse and re-typecheck the packages that actually
+// changed.
+type Runner struct {
+	imp    *importer.Importer
+	loader *importer.Loader
+}
+
+// NewRunner constructs a Runner whose Importer is rooted at dir.
+func NewRunner(dir string, log logr.Logger) *Runner {
+	imp, loader := importer.NewImporter(dir, log.WithName("importer"))
+	return &Runner{imp: imp, loader: loader}
+}
+
+// Run applies cfg.Fixes to every package matching cfg.Patterns, reusing any
+// packages this Runner already loaded whose files haven't changed since.
+func (r *Runner) Run(ctx context.Context, cfg Config) (Results, error) {
+	var results Results
+
+	fixes, err := buildFixes(cfg.Fixes, cfg.FixConfig)
+	if err != nil {
+		return results, err
+	}
+
+	r.imp.Overlay = cfg.Overlay
+
+	handler := &resultHandler{
+		fileSet: r.loader.FileSet(),
+		onFix:   cfg.OnFix,
+		results: &results,
+	}
+	fx := &fixer.Fixer{
+		Log:       cfg.Log.WithName("fixer"),
+		Fixes:     fixes,
+		Loader:    r.loader,
+		Overlay:   cfg.Overlay,
+		HandleFix: handler.handleFix,
+	}
+
+	for _, pattern := range cfg.Patterns {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		default:
+		}
+
+		pkgs, err := r.imp.Import(pattern)
+		if err != nil {
+			return results, fmt.Errorf("importing %q: %w", pattern, err)
+		}
+		for _, pkg := range pkgs {
+			if err := fx.FixPackage(pkg); err != nil {
+				return results, fmt.Errorf("fixing %q: %w", pkg.PkgPath, err)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// buildFixes resolves each named fix via the fixer registry, configuring it
+// from fixCfg.
+func buildFixes(names []string, fixCfg map[string]string) ([]fixer.Fix, error) {
+	fixes := make([]fixer.Fix, 0, len(names))
+	for _, name := range names {
+		factory, ok := fixer.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown fix %q", name)
+		}
+		fix, err := factory(fixCfg)
+		if err != nil {
+			return nil, fmt.Errorf("building fix %q: %w", name, err)
+		}
+		fixes = append(fixes, fix)
+	}
+	return fixes, nil
+}
+
+// resultHandler adapts fixer.Fixer's HandleFix callback to Config.OnFix and
+// records each changed file in Results.
+type resultHandler struct {
+	fileSet *token.FileSet
+	onFix   func(info fixer.FileInfo, originalSrc, newSrc []byte) error
+	results *Results
+}
+
+func (h *resultHandler) handleFix(info fixer.FileInfo) error {
+	newSrc, err := fixer.GofmtFile(info.AST, h.fileSet)
+	if err != nil {
+		return err
+	}
+
+	h.results.Fixed = append(h.results.Fixed, info.Name)
+
+	if h.onFix != nil {
+		return h.onFix(info, info.OrigSrc, newSrc)
+	}
+	return ioutil.WriteFile(info.Name, newSrc, 0)
+}
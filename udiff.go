@@ -0,0 +1,133 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/thockin/klog-to-logr/internal/diffutil"
+)
+
+// contextLines is the number of unchanged lines of context diff prints
+// around each hunk, matching `diff -u`'s default.
+const contextLines = 3
+
+// diff computes a unified diff between b1 and b2, byte-for-byte compatible
+// with `diff -u` for the cases kfix relies on: hunks with contextLines of
+// context, and a "\ No newline at end of file" marker for either input that
+// doesn't end in a newline.
+func diff(b1, b2 []byte) ([]byte, error) {
+	aLines, aFinalNL := diffutil.SplitLines(b1)
+	bLines, bFinalNL := diffutil.SplitLines(b2)
+
+	ops := diffutil.Diff(aLines, bLines)
+
+	var changed []int
+	for i, o := range ops {
+		if o.Kind != diffutil.Equal {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	type hunkRange struct{ lo, hi int } // inclusive indices into ops
+	var hunks []hunkRange
+	start, end := changed[0], changed[0]
+	for _, idx := range changed[1:] {
+		if idx-end <= 2*contextLines {
+			end = idx
+			continue
+		}
+		hunks = append(hunks, hunkRange{start, end})
+		start, end = idx, idx
+	}
+	hunks = append(hunks, hunkRange{start, end})
+
+	priorLine := func(ops []diffutil.Op, before int, side func(diffutil.Op) int) int {
+		for i := before - 1; i >= 0; i-- {
+			if idx := side(ops[i]); idx >= 0 {
+				return idx + 1
+			}
+		}
+		return 0
+	}
+	aSide := func(o diffutil.Op) int { return o.AIdx }
+	bSide := func(o diffutil.Op) int { return o.BIdx }
+
+	formatRange := func(start, count int) string {
+		if count == 1 {
+			return fmt.Sprintf("%d", start)
+		}
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("--- a\n")
+	buf.WriteString("+++ b\n")
+
+	for _, h := range hunks {
+		lo := h.lo - contextLines
+		if lo < 0 {
+			lo = 0
+		}
+		hi := h.hi + contextLines
+		if hi > len(ops)-1 {
+			hi = len(ops) - 1
+		}
+
+		aStart, bStart, aCount, bCount := -1, -1, 0, 0
+		for _, o := range ops[lo : hi+1] {
+			if o.AIdx >= 0 {
+				if aStart == -1 {
+					aStart = o.AIdx + 1
+				}
+				aCount++
+			}
+			if o.BIdx >= 0 {
+				if bStart == -1 {
+					bStart = o.BIdx + 1
+				}
+				bCount++
+			}
+		}
+		if aStart == -1 {
+			aStart = priorLine(ops, lo, aSide)
+		}
+		if bStart == -1 {
+			bStart = priorLine(ops, lo, bSide)
+		}
+
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", formatRange(aStart, aCount), formatRange(bStart, bCount))
+
+		for _, o := range ops[lo : hi+1] {
+			var prefix byte
+			var text string
+			switch o.Kind {
+			case diffutil.Equal:
+				prefix, text = ' ', aLines[o.AIdx]
+			case diffutil.Delete:
+				prefix, text = '-', aLines[o.AIdx]
+			case diffutil.Insert:
+				prefix, text = '+', bLines[o.BIdx]
+			}
+
+			buf.WriteByte(prefix)
+			buf.WriteString(text)
+			if !strings.HasSuffix(text, "\n") {
+				buf.WriteByte('\n')
+			}
+			if (o.AIdx >= 0 && o.AIdx == len(aLines)-1 && !aFinalNL) ||
+				(o.BIdx >= 0 && o.BIdx == len(bLines)-1 && !bFinalNL) {
+				buf.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
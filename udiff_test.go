@@ -0,0 +1,58 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     string
+		wantData string
+	}{
+		{
+			name:     "identical",
+			a:        "a\nb\nc\n",
+			b:        "a\nb\nc\n",
+			wantData: "",
+		},
+		{
+			name:     "single line changed",
+			a:        "a\nb\nc\n",
+			b:        "a\nB\nc\n",
+			wantData: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "deletion",
+			a:        "a\nb\nc\n",
+			b:        "a\nc\n",
+			wantData: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name:     "insertion",
+			a:        "a\nc\n",
+			b:        "a\nb\nc\n",
+			wantData: "--- a\n+++ b\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			name:     "missing trailing newline",
+			a:        "a\nb",
+			b:        "a\nb\n",
+			wantData: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := diff([]byte(c.a), []byte(c.b))
+			if err != nil {
+				t.Fatalf("diff returned error: %v", err)
+			}
+			if string(got) != c.wantData {
+				t.Errorf("diff(%q, %q) =\n%q\nwant\n%q", c.a, c.b, got, c.wantData)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+
+	"github.com/thockin/klog-to-logr/pkg/kfix"
+)
+
+// watch keeps cfg's importer warm (via run, which must share a single
+// kfix.Runner across calls) and re-runs it every time a .go file changes
+// under one of cfg.Patterns, streaming the results through run. Because
+// the shared Runner's Importer caches packages by a content signature,
+// unchanged packages are reused across iterations instead of being
+// re-parsed and re-typechecked, so a warm cache turns each re-run from
+// seconds into milliseconds on typical trees.
+func watch(ctx context.Context, log logr.Logger, cfg kfix.Config, run func(kfix.Config) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, pattern := range cfg.Patterns {
+		if err := addWatchDirs(watcher, cfg.Dir, pattern); err != nil {
+			log.Error(err, "unable to watch path", "path", pattern)
+		}
+	}
+
+	if err := run(cfg); err != nil {
+		log.Error(err, "initial fix failed")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Ext(event.Name) != ".go" {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			log.V(1).Info("file changed, re-fixing", "path", event.Name)
+			if err := run(cfg); err != nil {
+				log.Error(err, "fix failed")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(err, "watch error")
+		}
+	}
+}
+
+// addWatchDirs adds pattern's directory -- and, for a `...` wildcard,
+// every directory beneath it -- to watcher. pattern is resolved relative
+// to dir; patterns that turn out not to name anything on disk (e.g. a bare
+// import path like "k8s.io/klog") fall back to watching dir itself, since
+// that's the best a filesystem watcher can do for those.
+func addWatchDirs(watcher *fsnotify.Watcher, dir, pattern string) error {
+	recursive := strings.HasSuffix(pattern, "...")
+	base := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	if base == "" {
+		base = "."
+	}
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(dir, base)
+	}
+	if info, err := os.Stat(base); err != nil || !info.IsDir() {
+		base = dir
+		recursive = false
+	}
+	if !recursive {
+		return watcher.Add(base)
+	}
+
+	return filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}